@@ -4,9 +4,12 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/openfaas/faas-netes/pkg/k8s"
 	types "github.com/openfaas/faas-provider/types"
@@ -19,6 +22,7 @@ func MakeSecretHandler(defaultNamespace string, kube kubernetes.Interface) http.
 	handler := SecretsHandler{
 		LookupNamespace: NewNamespaceResolver(defaultNamespace, kube),
 		Secrets:         k8s.NewSecretsClient(kube),
+		Rotator:         NewRotationController(kube),
 	}
 	return handler.ServeHTTP
 }
@@ -27,6 +31,34 @@ func MakeSecretHandler(defaultNamespace string, kube kubernetes.Interface) http.
 type SecretsHandler struct {
 	Secrets         k8s.SecretsClient
 	LookupNamespace NamespaceResolver
+
+	// Rotator restarts function Deployments that depend on a secret
+	// whenever it is updated through replaceSecret. It is optional; when
+	// nil, updated secrets are not followed by a rotation.
+	Rotator *RotationController
+}
+
+// secretRequest is the wire format accepted by create/replace. It extends
+// the shared types.Secret contract with a Kubernetes secret type and a
+// map of key/value entries, mirroring corev1.Secret's own Data/StringData
+// split so that callers are never left guessing how a value is encoded:
+// Data entries are base64-encoded, StringData entries are plain text.
+// The legacy single Value field is still accepted for backwards
+// compatibility.
+type secretRequest struct {
+	types.Secret
+	Type       string            `json:"type,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+	StringData map[string]string `json:"stringData,omitempty"`
+}
+
+// secretResponse is the wire format returned by List. It surfaces the
+// secret type alongside the existing name/namespace fields so that
+// callers can differentiate registry pull creds, TLS certs and plain
+// function secrets.
+type secretResponse struct {
+	types.Secret
+	Type string `json:"type,omitempty"`
 }
 
 func (h SecretsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -71,11 +103,14 @@ func (h SecretsHandler) listSecrets(namespace string, w http.ResponseWriter, r *
 		return
 	}
 
-	secrets := make([]types.Secret, len(res))
-	for idx, name := range res {
-		secrets[idx] = types.Secret{
-			Name:      name,
-			Namespace: namespace,
+	secrets := make([]secretResponse, len(res))
+	for idx, s := range res {
+		secrets[idx] = secretResponse{
+			Secret: types.Secret{
+				Name:      s.Name,
+				Namespace: namespace,
+			},
+			Type: string(s.Type),
 		}
 	}
 	secretsBytes, err := json.Marshal(secrets)
@@ -90,16 +125,26 @@ func (h SecretsHandler) listSecrets(namespace string, w http.ResponseWriter, r *
 }
 
 func (h SecretsHandler) createSecret(namespace string, w http.ResponseWriter, r *http.Request) {
-	secret := types.Secret{}
-	err := json.NewDecoder(r.Body).Decode(&secret)
+	started := time.Now()
+
+	req := secretRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		log.Printf("Secret unmarshal error: %v\n", err)
 		return
 	}
 
-	secret.Namespace = namespace
+	req.Namespace = namespace
+	secret, err := asK8sSecret(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Secret data error: %v\n", err)
+		return
+	}
+
 	err = h.Secrets.Create(secret)
+	auditSecretOperation(r, "create", namespace, secret.Name, started, err)
 	if err != nil {
 		status, reason := ProcessErrorReasons(err)
 		log.Printf("Secret create error reason: %s, %v\n", reason, err)
@@ -111,16 +156,26 @@ func (h SecretsHandler) createSecret(namespace string, w http.ResponseWriter, r
 }
 
 func (h SecretsHandler) replaceSecret(namespace string, w http.ResponseWriter, r *http.Request) {
-	secret := types.Secret{}
-	err := json.NewDecoder(r.Body).Decode(&secret)
+	started := time.Now()
+
+	req := secretRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		log.Printf("Secret unmarshal error: %v\n", err)
 		return
 	}
 
-	secret.Namespace = namespace
+	req.Namespace = namespace
+	secret, err := asK8sSecret(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Secret data error: %v\n", err)
+		return
+	}
+
 	err = h.Secrets.Replace(secret)
+	auditSecretOperation(r, "replace", namespace, secret.Name, started, err)
 	if err != nil {
 		status, reason := ProcessErrorReasons(err)
 		log.Printf("Secret update error reason: %s, %v\n", reason, err)
@@ -128,10 +183,22 @@ func (h SecretsHandler) replaceSecret(namespace string, w http.ResponseWriter, r
 		return
 	}
 	log.Printf("Secret %s updated", secret.Name)
+
+	if h.Rotator != nil {
+		restarted, err := h.Rotator.Rotate(namespace, secret.Name)
+		if err != nil {
+			log.Printf("Secret %s rotation error: %v\n", secret.Name, err)
+		} else if len(restarted) > 0 {
+			log.Printf("Secret %s rotation restarted deployments: %v\n", secret.Name, restarted)
+		}
+	}
+
 	w.WriteHeader(http.StatusAccepted)
 }
 
 func (h SecretsHandler) deleteSecret(namespace string, w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
+
 	secret := types.Secret{}
 	err := json.NewDecoder(r.Body).Decode(&secret)
 	if err != nil {
@@ -141,6 +208,7 @@ func (h SecretsHandler) deleteSecret(namespace string, w http.ResponseWriter, r
 	}
 
 	err = h.Secrets.Delete(namespace, secret.Name)
+	auditSecretOperation(r, "delete", namespace, secret.Name, started, err)
 	if err != nil {
 		status, reason := ProcessErrorReasons(err)
 		log.Printf("Secret delete error reason: %s, %v\n", reason, err)
@@ -150,3 +218,40 @@ func (h SecretsHandler) deleteSecret(namespace string, w http.ResponseWriter, r
 	log.Printf("Secret %s deleted\n", secret.Name)
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// asK8sSecret converts the wire-format secretRequest into a k8s.Secret.
+// Data entries are decoded from base64, StringData entries are taken
+// literally, and, when neither Data nor StringData has entries, the legacy
+// single Value field is stored under a key matching the secret's own name
+// so that it mounts as a file of the same name; otherwise Value is
+// ignored. It is an error for the same key to appear in both Data and
+// StringData, since the caller's intent would be ambiguous.
+func asK8sSecret(req secretRequest) (k8s.Secret, error) {
+	data := map[string][]byte{}
+
+	for key, value := range req.Data {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return k8s.Secret{}, fmt.Errorf("data[%s] is not valid base64: %w", key, err)
+		}
+		data[key] = decoded
+	}
+
+	for key, value := range req.StringData {
+		if _, exists := data[key]; exists {
+			return k8s.Secret{}, fmt.Errorf("key %s set in both data and stringData", key)
+		}
+		data[key] = []byte(value)
+	}
+
+	if len(data) == 0 && req.Value != "" {
+		data[req.Name] = []byte(req.Value)
+	}
+
+	return k8s.Secret{
+		Name:      req.Name,
+		Namespace: req.Namespace,
+		Type:      k8s.SecretType(req.Type),
+		Data:      data,
+	}, nil
+}
@@ -0,0 +1,186 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// secretRevisionAnnotationPrefix is bumped on a function's pod
+	// template for every secret it depends on that changes, so that the
+	// Deployment's pod template hash changes and Kubernetes performs a
+	// rolling restart.
+	secretRevisionAnnotationPrefix = "openfaas.com/secret-revision-"
+
+	// secretRotateOptOutAnnotation, set to "false" on a function
+	// Deployment's pod template, excludes it from automatic rotation even
+	// if its pod spec references the updated secret.
+	secretRotateOptOutAnnotation = "openfaas.com/rotate-secrets"
+)
+
+// RotationController finds Deployments that depend on a given secret and
+// triggers a rolling restart by bumping a per-secret revision annotation
+// on their pod template.
+type RotationController struct {
+	Kube kubernetes.Interface
+}
+
+// NewRotationController returns a RotationController backed by kube.
+func NewRotationController(kube kubernetes.Interface) *RotationController {
+	return &RotationController{Kube: kube}
+}
+
+// Rotate restarts every Deployment in namespace whose pod spec references
+// secretName, unless it has opted out, and returns the names of the
+// Deployments it restarted.
+func (c *RotationController) Rotate(namespace, secretName string) ([]string, error) {
+	deployments, err := c.Kube.AppsV1().
+		Deployments(namespace).
+		List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list deployments in %s: %w", namespace, err)
+	}
+
+	restarted := []string{}
+	revision := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+
+		if deployment.Spec.Template.Annotations[secretRotateOptOutAnnotation] == "false" {
+			continue
+		}
+
+		if !dependsOnSecret(deployment, secretName) {
+			continue
+		}
+
+		if deployment.Spec.Template.Annotations == nil {
+			deployment.Spec.Template.Annotations = map[string]string{}
+		}
+		deployment.Spec.Template.Annotations[secretRevisionAnnotationPrefix+secretName] = revision
+
+		_, err := c.Kube.AppsV1().
+			Deployments(namespace).
+			Update(context.Background(), deployment, metav1.UpdateOptions{})
+		if err != nil {
+			return restarted, fmt.Errorf("unable to restart deployment %s.%s: %w", deployment.Name, namespace, err)
+		}
+
+		restarted = append(restarted, deployment.Name)
+	}
+
+	return restarted, nil
+}
+
+// dependsOnSecret reports whether deployment's pod spec mounts secretName
+// as a volume (directly or as a projected volume source), or pulls it in
+// via a container's envFrom.
+func dependsOnSecret(deployment *appsv1.Deployment, secretName string) bool {
+	spec := deployment.Spec.Template.Spec
+
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == secretName {
+			return true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil && source.Secret.Name == secretName {
+					return true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.Containers...), spec.InitContainers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == secretName {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// secretRotateRequest is the body accepted by the rotate endpoint: the
+// name of a secret to force a rotation for, scoped to the resolved
+// namespace.
+type secretRotateRequest struct {
+	Name string `json:"name"`
+}
+
+// secretRotateResponse reports which Deployments were restarted by a
+// forced rotation.
+type secretRotateResponse struct {
+	Name      string   `json:"name"`
+	Restarted []string `json:"restarted"`
+}
+
+// MakeSecretRotateHandler makes a handler for POST /system/secrets/rotate,
+// which forces the same rolling restart that normally follows a
+// `faas-cli secret update` on demand, without requiring the secret's
+// value to actually change.
+func MakeSecretRotateHandler(defaultNamespace string, kube kubernetes.Interface) http.HandlerFunc {
+	handler := SecretsHandler{
+		LookupNamespace: NewNamespaceResolver(defaultNamespace, kube),
+		Rotator:         NewRotationController(kube),
+	}
+	return handler.rotateSecret
+}
+
+func (h SecretsHandler) rotateSecret(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+
+	namespace, err := h.LookupNamespace(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := secretRotateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Secret rotate unmarshal error: %v\n", err)
+		return
+	}
+
+	restarted, err := h.Rotator.Rotate(namespace, req.Name)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("Secret rotate error: %v\n", err)
+		return
+	}
+
+	body, err := json.Marshal(secretRotateResponse{Name: req.Name, Restarted: restarted})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("Secret rotate json marshal error: %v\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,100 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package handlers
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestDependsOnSecret(t *testing.T) {
+	cases := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		secretName string
+		want       bool
+	}{
+		{
+			name: "mounted as a volume",
+			deployment: deploymentWithVolume("other"),
+			secretName: "other",
+			want:       true,
+		},
+		{
+			name:       "mounted as a different volume",
+			deployment: deploymentWithVolume("other"),
+			secretName: "unrelated",
+			want:       false,
+		},
+		{
+			name:       "referenced via container envFrom",
+			deployment: deploymentWithEnvFrom(false, "other"),
+			secretName: "other",
+			want:       true,
+		},
+		{
+			name:       "referenced via init container envFrom",
+			deployment: deploymentWithEnvFrom(true, "other"),
+			secretName: "other",
+			want:       true,
+		},
+		{
+			name:       "no volumes or envFrom references",
+			deployment: &appsv1.Deployment{},
+			secretName: "other",
+			want:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := dependsOnSecret(tc.deployment, tc.secretName); got != tc.want {
+				t.Errorf("dependsOnSecret(..., %q) = %v, want %v", tc.secretName, got, tc.want)
+			}
+		})
+	}
+}
+
+func deploymentWithVolume(secretName string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{
+						{
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func deploymentWithEnvFrom(init bool, secretName string) *appsv1.Deployment {
+	container := corev1.Container{
+		EnvFrom: []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			}},
+		},
+	}
+
+	spec := corev1.PodSpec{}
+	if init {
+		spec.InitContainers = []corev1.Container{container}
+	} else {
+		spec.Containers = []corev1.Container{container}
+	}
+
+	return &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{Spec: spec},
+		},
+	}
+}
@@ -0,0 +1,197 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/openfaas/faas-netes/pkg/k8s"
+	"k8s.io/client-go/kubernetes"
+)
+
+// secretSyncStatus is the outcome recorded against a single secret in a
+// sync request.
+type secretSyncStatus string
+
+const (
+	secretSyncCreated   secretSyncStatus = "created"
+	secretSyncUpdated   secretSyncStatus = "updated"
+	secretSyncUnchanged secretSyncStatus = "unchanged"
+	secretSyncDeleted   secretSyncStatus = "deleted"
+	secretSyncError     secretSyncStatus = "error"
+)
+
+// secretSyncRequest is the body accepted by the sync endpoint: the full
+// desired set of secrets for a namespace.
+type secretSyncRequest struct {
+	Secrets []secretRequest `json:"secrets"`
+
+	// Prune removes managed secrets in the namespace that are not present
+	// in Secrets. It defaults to false so that callers opt-in explicitly.
+	Prune bool `json:"prune,omitempty"`
+}
+
+// secretSyncResult reports what happened to a single secret as part of a
+// sync, so CI pipelines and GitOps controllers can verify the outcome of
+// a declarative update without iterating one-by-one through the CRUD
+// endpoints.
+type secretSyncResult struct {
+	Name   string           `json:"name"`
+	Status secretSyncStatus `json:"status"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// MakeSecretSyncHandler makes a handler that reconciles the full set of
+// secrets in a namespace against the desired state given in the request
+// body, creating, updating and, when Prune is set, deleting secrets so
+// that the namespace matches in a single call.
+func MakeSecretSyncHandler(defaultNamespace string, kube kubernetes.Interface) http.HandlerFunc {
+	handler := SecretsHandler{
+		LookupNamespace: NewNamespaceResolver(defaultNamespace, kube),
+		Secrets:         k8s.NewSecretsClient(kube),
+		Rotator:         NewRotationController(kube),
+	}
+	return handler.syncSecrets
+}
+
+func (h SecretsHandler) syncSecrets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+
+	namespace, err := h.LookupNamespace(r)
+	if err != nil {
+		switch err.Error() {
+		case "unable to manage secrets within the specified namespace":
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		return
+	}
+
+	req := secretSyncRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Secret sync unmarshal error: %v\n", err)
+		return
+	}
+
+	existing, err := h.Secrets.List(namespace)
+	if err != nil {
+		status, reason := ProcessErrorReasons(err)
+		log.Printf("Secret sync list error reason: %s, %v\n", reason, err)
+		w.WriteHeader(status)
+		return
+	}
+
+	wanted := make(map[string]bool, len(req.Secrets))
+	results := make([]secretSyncResult, 0, len(req.Secrets))
+
+	for _, item := range req.Secrets {
+		item.Namespace = namespace
+		wanted[item.Name] = true
+		results = append(results, h.syncOne(r, namespace, item))
+	}
+
+	if req.Prune {
+		for _, s := range existing {
+			if wanted[s.Name] {
+				continue
+			}
+
+			started := time.Now()
+			err := h.Secrets.Delete(namespace, s.Name)
+			auditSecretOperation(r, "delete", namespace, s.Name, started, err)
+			if err != nil {
+				results = append(results, secretSyncResult{Name: s.Name, Status: secretSyncError, Error: err.Error()})
+				continue
+			}
+			results = append(results, secretSyncResult{Name: s.Name, Status: secretSyncDeleted})
+		}
+	}
+
+	body, err := json.Marshal(results)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Printf("Secret sync json marshal error: %v\n", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// syncOne reconciles a single secret against the cluster, creating it if
+// absent, updating it if its contents have drifted, or reporting it
+// unchanged.
+func (h SecretsHandler) syncOne(r *http.Request, namespace string, req secretRequest) secretSyncResult {
+	desired, err := asK8sSecret(req)
+	if err != nil {
+		return secretSyncResult{Name: req.Name, Status: secretSyncError, Error: err.Error()}
+	}
+
+	current, err := h.Secrets.Get(namespace, req.Name)
+	if k8s.IsNotFound(err) {
+		started := time.Now()
+		err := h.Secrets.Create(desired)
+		auditSecretOperation(r, "create", namespace, req.Name, started, err)
+		if err != nil {
+			return secretSyncResult{Name: req.Name, Status: secretSyncError, Error: err.Error()}
+		}
+		return secretSyncResult{Name: req.Name, Status: secretSyncCreated}
+	}
+	if err != nil {
+		return secretSyncResult{Name: req.Name, Status: secretSyncError, Error: err.Error()}
+	}
+
+	if secretsEqual(current, desired) {
+		return secretSyncResult{Name: req.Name, Status: secretSyncUnchanged}
+	}
+
+	started := time.Now()
+	err = h.Secrets.Replace(desired)
+	auditSecretOperation(r, "replace", namespace, req.Name, started, err)
+	if err != nil {
+		return secretSyncResult{Name: req.Name, Status: secretSyncError, Error: err.Error()}
+	}
+
+	if h.Rotator != nil {
+		if _, err := h.Rotator.Rotate(namespace, req.Name); err != nil {
+			log.Printf("Secret %s rotation error: %v\n", req.Name, err)
+		}
+	}
+
+	return secretSyncResult{Name: req.Name, Status: secretSyncUpdated}
+}
+
+func secretsEqual(a, b k8s.Secret) bool {
+	if a.Type == "" {
+		a.Type = k8s.SecretTypeOpaque
+	}
+	if b.Type == "" {
+		b.Type = k8s.SecretTypeOpaque
+	}
+	if a.Type != b.Type || len(a.Data) != len(b.Data) {
+		return false
+	}
+
+	for key, value := range a.Data {
+		other, ok := b.Data[key]
+		if !ok || string(other) != string(value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,133 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-netes/pkg/k8s"
+)
+
+func TestSecretsEqual(t *testing.T) {
+	cases := []struct {
+		name string
+		a    k8s.Secret
+		b    k8s.Secret
+		want bool
+	}{
+		{
+			name: "identical data and type",
+			a:    k8s.Secret{Type: k8s.SecretTypeOpaque, Data: map[string][]byte{"k": []byte("v")}},
+			b:    k8s.Secret{Type: k8s.SecretTypeOpaque, Data: map[string][]byte{"k": []byte("v")}},
+			want: true,
+		},
+		{
+			name: "defaults empty type to opaque on both sides",
+			a:    k8s.Secret{Data: map[string][]byte{"k": []byte("v")}},
+			b:    k8s.Secret{Type: k8s.SecretTypeOpaque, Data: map[string][]byte{"k": []byte("v")}},
+			want: true,
+		},
+		{
+			name: "differing type",
+			a:    k8s.Secret{Type: k8s.SecretTypeOpaque, Data: map[string][]byte{"k": []byte("v")}},
+			b:    k8s.Secret{Type: k8s.SecretTypeTLS, Data: map[string][]byte{"k": []byte("v")}},
+			want: false,
+		},
+		{
+			name: "differing value for the same key",
+			a:    k8s.Secret{Data: map[string][]byte{"k": []byte("v1")}},
+			b:    k8s.Secret{Data: map[string][]byte{"k": []byte("v2")}},
+			want: false,
+		},
+		{
+			name: "differing key set of the same length",
+			a:    k8s.Secret{Data: map[string][]byte{"a": []byte("v")}},
+			b:    k8s.Secret{Data: map[string][]byte{"b": []byte("v")}},
+			want: false,
+		},
+		{
+			name: "differing number of keys",
+			a:    k8s.Secret{Data: map[string][]byte{"a": []byte("v")}},
+			b:    k8s.Secret{Data: map[string][]byte{"a": []byte("v"), "b": []byte("v")}},
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := secretsEqual(tc.a, tc.b); got != tc.want {
+				t.Errorf("secretsEqual(%+v, %+v) = %v, want %v", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+// fakeSecretsClient is a minimal k8s.SecretsClient stand-in used to test
+// the sync handler's prune branch in isolation from the Kubernetes API.
+type fakeSecretsClient struct {
+	listed []k8s.SecretSummary
+
+	// deleteErr is returned by Delete, mirroring secretsClient.Delete
+	// treating a missing remote-backed secret as already-deleted rather
+	// than an error.
+	deleteErr error
+}
+
+func (f *fakeSecretsClient) Create(k8s.Secret) error             { return nil }
+func (f *fakeSecretsClient) Replace(k8s.Secret) error            { return nil }
+func (f *fakeSecretsClient) Delete(namespace, name string) error { return f.deleteErr }
+func (f *fakeSecretsClient) List(namespace string) ([]k8s.SecretSummary, error) {
+	return f.listed, nil
+}
+func (f *fakeSecretsClient) Get(namespace, name string) (k8s.Secret, error) {
+	return k8s.Secret{}, nil
+}
+func (f *fakeSecretsClient) Refresh(namespace, name string) (bool, error) { return false, nil }
+
+// TestSyncSecretsPruneIdempotentForRemoteBackedSecrets covers the prune
+// branch against a namespace where List still reports a secret that was
+// already deleted but whose remote entry survived (the zombie scenario that existed before
+// secretsClient.Delete was fixed to also clear the remote entry and
+// marker ConfigMap). Pruning it again must report it as deleted, not as
+// an error.
+func TestSyncSecretsPruneIdempotentForRemoteBackedSecrets(t *testing.T) {
+	fake := &fakeSecretsClient{
+		listed: []k8s.SecretSummary{
+			{Name: "zombie", Type: k8s.SecretTypeOpaque},
+		},
+	}
+
+	h := SecretsHandler{
+		Secrets: fake,
+		LookupNamespace: func(r *http.Request) (string, error) {
+			return "openfaas-fn", nil
+		},
+	}
+
+	body := strings.NewReader(`{"secrets":[],"prune":true}`)
+	req := httptest.NewRequest(http.MethodPost, "/system/secrets/sync", body)
+	rec := httptest.NewRecorder()
+
+	h.syncSecrets(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var results []secretSyncResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("unable to unmarshal response: %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if results[0].Status != secretSyncDeleted {
+		t.Errorf("got status %q, want %q: %+v", results[0].Status, secretSyncDeleted, results[0])
+	}
+}
@@ -0,0 +1,84 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package handlers
+
+import (
+	"testing"
+
+	types "github.com/openfaas/faas-provider/types"
+)
+
+func TestAsK8sSecret(t *testing.T) {
+	cases := []struct {
+		name    string
+		req     secretRequest
+		want    map[string][]byte
+		wantErr bool
+	}{
+		{
+			name: "decodes base64 data entries",
+			req: secretRequest{
+				Secret: types.Secret{Name: "sec", Namespace: "fn"},
+				Data:   map[string]string{"token": "aGVsbG8="},
+			},
+			want: map[string][]byte{"token": []byte("hello")},
+		},
+		{
+			name: "takes stringData literally",
+			req: secretRequest{
+				Secret:     types.Secret{Name: "sec", Namespace: "fn"},
+				StringData: map[string]string{"token": "hello"},
+			},
+			want: map[string][]byte{"token": []byte("hello")},
+		},
+		{
+			name: "errors when a key is set in both data and stringData",
+			req: secretRequest{
+				Secret:     types.Secret{Name: "sec", Namespace: "fn"},
+				Data:       map[string]string{"token": "aGVsbG8="},
+				StringData: map[string]string{"token": "hello"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "errors on invalid base64",
+			req: secretRequest{
+				Secret: types.Secret{Name: "sec", Namespace: "fn"},
+				Data:   map[string]string{"token": "not-base64!"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "falls back to the legacy Value field keyed by name",
+			req: secretRequest{
+				Secret: types.Secret{Name: "sec", Namespace: "fn", Value: "hello"},
+			},
+			want: map[string][]byte{"sec": []byte("hello")},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := asK8sSecret(tc.req)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got.Data) != len(tc.want) {
+				t.Fatalf("got %d data entries, want %d", len(got.Data), len(tc.want))
+			}
+			for key, value := range tc.want {
+				if string(got.Data[key]) != string(value) {
+					t.Errorf("data[%s] = %q, want %q", key, got.Data[key], value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,514 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-netes/pkg/k8s/secretstore"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// secretManagedByLabel marks secrets owned by OpenFaaS so they can be
+	// safely distinguished from secrets created by other tooling in the
+	// same namespace.
+	secretManagedByLabel = "app.kubernetes.io/managed-by"
+	secretManagedByValue = "openfaas"
+
+	// secretProviderAnnotation, set on a Namespace, selects the external
+	// secretstore.Provider that backs OpenFaaS secrets created in it.
+	// When absent, secrets are stored purely as Kubernetes Secrets.
+	secretProviderAnnotation = "secrets.openfaas.com/provider"
+
+	// secretPathPrefixAnnotation, set on a Namespace, is prepended to a
+	// secret's name to form its path in the external provider.
+	secretPathPrefixAnnotation = "secrets.openfaas.com/path-prefix"
+
+	// secretProviderConfigAnnotationPrefix namespaces non-sensitive
+	// provider settings (e.g. "mount", "region", "project") as
+	// annotations: "secrets.openfaas.com/provider-config-<key>": "<value>".
+	secretProviderConfigAnnotationPrefix = "secrets.openfaas.com/provider-config-"
+
+	// secretProviderCredentialsAnnotation, set on a Namespace, names a
+	// Kubernetes Secret in the same namespace whose Data is merged into
+	// the provider configuration. Credentials are never read from
+	// annotations directly: annotations are visible to anyone who can
+	// `kubectl get ns -o yaml`, which is a much weaker RBAC boundary than
+	// the one guarding Secrets.
+	secretProviderCredentialsAnnotation = "secrets.openfaas.com/provider-credentials"
+
+	// secretRemoteRevisionAnnotation is stamped onto the projected
+	// Kubernetes Secret with the external provider's revision for the
+	// data it currently holds, so the reconciler can detect drift
+	// without re-fetching and diffing the full payload.
+	secretRemoteRevisionAnnotation = "secrets.openfaas.com/remote-revision"
+)
+
+// SecretType identifies the underlying Kubernetes secret type to create,
+// mirroring the subset of corev1.SecretType values that OpenFaaS knows how
+// to bind to a function.
+type SecretType string
+
+const (
+	// SecretTypeOpaque is the default secret type, used for arbitrary
+	// function secrets mounted as files.
+	SecretTypeOpaque SecretType = "Opaque"
+
+	// SecretTypeDockerConfigJSON is used for image pull credentials.
+	SecretTypeDockerConfigJSON SecretType = "kubernetes.io/dockerconfigjson"
+
+	// SecretTypeTLS is used for TLS certificate/key pairs.
+	SecretTypeTLS SecretType = "kubernetes.io/tls"
+
+	// SecretTypeServiceAccountToken is used for projected service account
+	// tokens.
+	SecretTypeServiceAccountToken SecretType = "kubernetes.io/service-account-token"
+)
+
+// Secret is the internal representation of an OpenFaaS secret, decoupled
+// from the wire format used by the handlers package. Data holds one or
+// more key/value entries; a legacy single-value secret is represented with
+// a single entry keyed by the secret's own name.
+type Secret struct {
+	Name      string
+	Namespace string
+	Type      SecretType
+	Data      map[string][]byte
+}
+
+// SecretSummary is the minimal information about a secret returned by
+// List, without exposing its contents.
+type SecretSummary struct {
+	Name string
+	Type SecretType
+}
+
+// SecretsClient is the interface used by SecretsHandler to manage secrets
+// in the Kubernetes API. Namespaces annotated with secretProviderAnnotation
+// are additionally backed by an external secretstore.Provider: Create and
+// Replace also write through to it (Create after the Kubernetes Secret has
+// been created, Replace before the Kubernetes Secret is updated), and
+// Refresh re-pulls it to pick up rotations performed outside of OpenFaaS.
+type SecretsClient interface {
+	Create(secret Secret) error
+	Replace(secret Secret) error
+	Delete(namespace, name string) error
+	List(namespace string) ([]SecretSummary, error)
+	Get(namespace, name string) (Secret, error)
+
+	// Refresh re-reads a secret from its namespace's external provider,
+	// if any, and reports whether the projected Kubernetes Secret was
+	// updated as a result.
+	Refresh(namespace, name string) (changed bool, err error)
+}
+
+// NewSecretsClient returns a SecretsClient backed by the Kubernetes
+// Secrets API and, for namespaces annotated with secretProviderAnnotation,
+// by the external secretstore.Provider those annotations select.
+func NewSecretsClient(kube kubernetes.Interface) SecretsClient {
+	return &secretsClient{
+		kube: kube,
+	}
+}
+
+type secretsClient struct {
+	kube kubernetes.Interface
+}
+
+func (c *secretsClient) Create(secret Secret) error {
+	// Attempt the Kubernetes object first. Only once it exists do we
+	// write through to the external provider, so a failed Create (name
+	// collision, quota, RBAC) never clobbers whatever the remote store
+	// already held at this path.
+	req := asKubernetesSecret(secret, "", nil)
+
+	created, err := c.kube.CoreV1().
+		Secrets(secret.Namespace).
+		Create(context.Background(), req, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to create secret %s.%s: %w", secret.Name, secret.Namespace, err)
+	}
+
+	revision, owner, err := c.writeRemote(secret)
+	if err != nil {
+		if delErr := c.kube.CoreV1().Secrets(secret.Namespace).Delete(context.Background(), secret.Name, metav1.DeleteOptions{}); delErr != nil {
+			log.Printf("secrets: unable to roll back secret %s.%s after remote write failure: %v\n", secret.Name, secret.Namespace, delErr)
+		}
+		return err
+	}
+
+	if revision == "" {
+		return nil
+	}
+
+	created.Annotations = mergeAnnotation(created.Annotations, secretRemoteRevisionAnnotation, revision)
+	if owner != nil {
+		created.OwnerReferences = append(created.OwnerReferences, *owner)
+	}
+
+	if _, err := c.kube.CoreV1().Secrets(secret.Namespace).Update(context.Background(), created, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to stamp remote revision on secret %s.%s: %w", secret.Name, secret.Namespace, err)
+	}
+
+	return nil
+}
+
+func (c *secretsClient) Replace(secret Secret) error {
+	existing, err := c.kube.CoreV1().
+		Secrets(secret.Namespace).
+		Get(context.Background(), secret.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to fetch secret %s.%s for update: %w", secret.Name, secret.Namespace, err)
+	}
+
+	revision, owner, err := c.writeRemote(secret)
+	if err != nil {
+		return err
+	}
+
+	updated := asKubernetesSecret(secret, revision, owner)
+	updated.ResourceVersion = existing.ResourceVersion
+
+	_, err = c.kube.CoreV1().
+		Secrets(secret.Namespace).
+		Update(context.Background(), updated, metav1.UpdateOptions{})
+
+	if err != nil {
+		return fmt.Errorf("unable to update secret %s.%s: %w", secret.Name, secret.Namespace, err)
+	}
+
+	return nil
+}
+
+// writeRemote writes secret to its namespace's configured external
+// provider, if any, and returns the provider's revision for the stored
+// data and an OwnerReference tying the projected Secret's lifecycle to
+// its remote binding, or zero values when the namespace has no provider.
+func (c *secretsClient) writeRemote(secret Secret) (string, *metav1.OwnerReference, error) {
+	provider, prefix, err := c.providerFor(secret.Namespace)
+	if err != nil {
+		return "", nil, err
+	}
+	if provider == nil {
+		return "", nil, nil
+	}
+
+	revision, err := provider.Write(prefix+secret.Name, secret.Data)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to write secret %s to remote provider: %w", secret.Name, err)
+	}
+
+	owner, err := c.ensureRemoteBinding(secret.Namespace, secret.Name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return revision, owner, nil
+}
+
+// ensureRemoteBinding returns an OwnerReference to a lightweight marker
+// ConfigMap representing this secret's binding to its external store,
+// creating the marker if it doesn't already exist. Deleting the marker
+// garbage collects the projected Kubernetes Secret, giving remote-backed
+// secrets the same "owned" lifecycle guarantee the request asked for.
+func (c *secretsClient) ensureRemoteBinding(namespace, name string) (*metav1.OwnerReference, error) {
+	bindingName := name + "-secret-binding"
+
+	binding, err := c.kube.CoreV1().ConfigMaps(namespace).Get(context.Background(), bindingName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		binding, err = c.kube.CoreV1().ConfigMaps(namespace).Create(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bindingName,
+				Namespace: namespace,
+				Labels: map[string]string{
+					secretManagedByLabel: secretManagedByValue,
+				},
+			},
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to ensure remote binding for secret %s.%s: %w", name, namespace, err)
+	}
+
+	blockOwnerDeletion := true
+	return &metav1.OwnerReference{
+		APIVersion:         "v1",
+		Kind:               "ConfigMap",
+		Name:               binding.Name,
+		UID:                binding.UID,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}, nil
+}
+
+// providerFor resolves the secretstore.Provider configured for a
+// namespace via its annotations, along with the path prefix to apply to
+// secret names. It returns a nil provider when the namespace does not
+// opt into an external store.
+func (c *secretsClient) providerFor(namespace string) (secretstore.Provider, string, error) {
+	ns, err := c.kube.CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get namespace %s: %w", namespace, err)
+	}
+
+	kind := ns.Annotations[secretProviderAnnotation]
+	if kind == "" {
+		return nil, "", nil
+	}
+
+	config, err := c.providerConfig(namespace, ns.Annotations)
+	if err != nil {
+		return nil, "", err
+	}
+
+	provider, err := secretstore.NewProvider(secretstore.Kind(kind), config)
+	if err != nil {
+		return nil, "", fmt.Errorf("namespace %s: %w", namespace, err)
+	}
+
+	return provider, ns.Annotations[secretPathPrefixAnnotation], nil
+}
+
+// providerConfig builds the configuration map passed to a secretstore.
+// Provider. Non-sensitive settings come straight from namespace
+// annotations; credentials never do. Instead, secretProviderCredentialsAnnotation
+// names a Kubernetes Secret in the same namespace, and that Secret's Data
+// is merged in, keeping tokens out of the namespace object where any
+// caller able to `kubectl get ns` could read them.
+func (c *secretsClient) providerConfig(namespace string, annotations map[string]string) (map[string]string, error) {
+	config := map[string]string{}
+	for key, value := range annotations {
+		if name, ok := strings.CutPrefix(key, secretProviderConfigAnnotationPrefix); ok {
+			// "token" is a credential, so it is only taken from the
+			// credentials Secret below.
+			if name == "token" {
+				continue
+			}
+			config[name] = value
+		}
+	}
+
+	credentialsSecret := annotations[secretProviderCredentialsAnnotation]
+	if credentialsSecret == "" {
+		return config, nil
+	}
+
+	creds, err := c.kube.CoreV1().
+		Secrets(namespace).
+		Get(context.Background(), credentialsSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read provider credentials secret %s.%s: %w", credentialsSecret, namespace, err)
+	}
+
+	for key, value := range creds.Data {
+		config[key] = string(value)
+	}
+
+	return config, nil
+}
+
+// Refresh re-reads a secret from its namespace's configured external
+// provider, if any, and updates the projected Kubernetes Secret when the
+// remote revision has changed since it was last written. changed reports
+// whether an update was made, so callers (e.g. the rotation controller)
+// know when dependent Deployments need restarting.
+func (c *secretsClient) Refresh(namespace, name string) (bool, error) {
+	provider, prefix, err := c.providerFor(namespace)
+	if err != nil {
+		return false, err
+	}
+	if provider == nil {
+		return false, nil
+	}
+
+	data, revision, err := provider.Read(prefix + name)
+	if err != nil {
+		return false, fmt.Errorf("unable to read secret %s from remote provider: %w", name, err)
+	}
+
+	existing, err := c.kube.CoreV1().
+		Secrets(namespace).
+		Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("unable to get secret %s.%s: %w", name, namespace, err)
+	}
+
+	if existing.Annotations[secretRemoteRevisionAnnotation] == revision {
+		return false, nil
+	}
+
+	existing.Data = data
+	existing.Annotations = mergeAnnotation(existing.Annotations, secretRemoteRevisionAnnotation, revision)
+
+	_, err = c.kube.CoreV1().
+		Secrets(namespace).
+		Update(context.Background(), existing, metav1.UpdateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("unable to refresh secret %s.%s: %w", name, namespace, err)
+	}
+
+	return true, nil
+}
+
+func (c *secretsClient) Delete(namespace, name string) error {
+	if err := c.deleteRemote(namespace, name); err != nil {
+		return err
+	}
+
+	err := c.kube.CoreV1().
+		Secrets(namespace).
+		Delete(context.Background(), name, metav1.DeleteOptions{})
+
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete secret %s.%s: %w", name, namespace, err)
+	}
+
+	return nil
+}
+
+// deleteRemote removes a secret's value from its namespace's external
+// provider, if any, along with the marker ConfigMap created for it by
+// ensureRemoteBinding. Without this, a remote-backed secret's value
+// outlives its projected Kubernetes Secret indefinitely, the marker
+// ConfigMap leaks forever, and List keeps resurrecting the "deleted"
+// secret by merging it back in from the provider.
+func (c *secretsClient) deleteRemote(namespace, name string) error {
+	provider, prefix, err := c.providerFor(namespace)
+	if err != nil {
+		return err
+	}
+	if provider == nil {
+		return nil
+	}
+
+	if err := provider.Delete(prefix + name); err != nil {
+		return fmt.Errorf("unable to delete secret %s from remote provider: %w", name, err)
+	}
+
+	bindingName := name + "-secret-binding"
+	err = c.kube.CoreV1().ConfigMaps(namespace).Delete(context.Background(), bindingName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete remote binding for secret %s.%s: %w", name, namespace, err)
+	}
+
+	return nil
+}
+
+func (c *secretsClient) List(namespace string) ([]SecretSummary, error) {
+	list, err := c.kube.CoreV1().
+		Secrets(namespace).
+		List(context.Background(), metav1.ListOptions{
+			LabelSelector: secretManagedByLabel + "=" + secretManagedByValue,
+		})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to list secrets in %s: %w", namespace, err)
+	}
+
+	byName := make(map[string]SecretSummary, len(list.Items))
+	for _, item := range list.Items {
+		byName[item.Name] = SecretSummary{
+			Name: item.Name,
+			Type: SecretType(item.Type),
+		}
+	}
+
+	// Merge in anything that exists in the namespace's external provider
+	// but hasn't been projected locally yet (or was deleted locally
+	// while still present remotely), per the sync requirement that List
+	// reflect the full remote+local view.
+	provider, prefix, err := c.providerFor(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if provider != nil {
+		remoteNames, err := provider.List(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list secrets in remote provider for %s: %w", namespace, err)
+		}
+
+		for _, name := range remoteNames {
+			if _, ok := byName[name]; !ok {
+				byName[name] = SecretSummary{Name: name, Type: SecretTypeOpaque}
+			}
+		}
+	}
+
+	summaries := make([]SecretSummary, 0, len(byName))
+	for _, s := range byName {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	return summaries, nil
+}
+
+func (c *secretsClient) Get(namespace, name string) (Secret, error) {
+	item, err := c.kube.CoreV1().
+		Secrets(namespace).
+		Get(context.Background(), name, metav1.GetOptions{})
+
+	if err != nil {
+		return Secret{}, fmt.Errorf("unable to get secret %s.%s: %w", name, namespace, err)
+	}
+
+	return Secret{
+		Name:      item.Name,
+		Namespace: item.Namespace,
+		Type:      SecretType(item.Type),
+		Data:      item.Data,
+	}, nil
+}
+
+func asKubernetesSecret(secret Secret, remoteRevision string, owner *metav1.OwnerReference) *corev1.Secret {
+	secretType := secret.Type
+	if secretType == "" {
+		secretType = SecretTypeOpaque
+	}
+
+	var annotations map[string]string
+	if remoteRevision != "" {
+		annotations = map[string]string{secretRemoteRevisionAnnotation: remoteRevision}
+	}
+
+	var owners []metav1.OwnerReference
+	if owner != nil {
+		owners = []metav1.OwnerReference{*owner}
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secret.Name,
+			Namespace: secret.Namespace,
+			Labels: map[string]string{
+				secretManagedByLabel: secretManagedByValue,
+			},
+			Annotations:     annotations,
+			OwnerReferences: owners,
+		},
+		Type: corev1.SecretType(secretType),
+		Data: secret.Data,
+	}
+}
+
+func mergeAnnotation(annotations map[string]string, key, value string) map[string]string {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[key] = value
+	return annotations
+}
+
+// IsNotFound returns true if the given error indicates that the secret
+// does not exist in the cluster.
+func IsNotFound(err error) bool {
+	return apierrors.IsNotFound(err)
+}
@@ -0,0 +1,82 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package k8s
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// SecretsReconciler periodically refreshes Kubernetes Secrets that are
+// projected from an external secretstore.Provider, so that rotations
+// performed directly against Vault/AWS/GCP outside of OpenFaaS are
+// picked up without waiting for the next `faas-cli secret update`.
+type SecretsReconciler struct {
+	Client   SecretsClient
+	Interval time.Duration
+
+	// Namespaces lists the namespaces to reconcile on each tick.
+	Namespaces func() ([]string, error)
+
+	// Rotate, when set, is called with every secret whose refresh pulled
+	// in new data from its external provider, so that Deployments
+	// depending on it are restarted the same way they would be after a
+	// `faas-cli secret update`. It is a plain function rather than a
+	// *handlers.RotationController to avoid pkg/k8s importing
+	// pkg/handlers.
+	Rotate func(namespace, name string) ([]string, error)
+}
+
+// Start runs the reconciliation loop until ctx is cancelled.
+func (r *SecretsReconciler) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcileOnce()
+		}
+	}
+}
+
+func (r *SecretsReconciler) reconcileOnce() {
+	namespaces, err := r.Namespaces()
+	if err != nil {
+		log.Printf("secrets reconciler: unable to list namespaces: %v\n", err)
+		return
+	}
+
+	for _, namespace := range namespaces {
+		secrets, err := r.Client.List(namespace)
+		if err != nil {
+			log.Printf("secrets reconciler: unable to list secrets in %s: %v\n", namespace, err)
+			continue
+		}
+
+		for _, secret := range secrets {
+			changed, err := r.Client.Refresh(namespace, secret.Name)
+			if err != nil {
+				log.Printf("secrets reconciler: unable to refresh %s.%s: %v\n", secret.Name, namespace, err)
+				continue
+			}
+
+			if !changed || r.Rotate == nil {
+				continue
+			}
+
+			restarted, err := r.Rotate(namespace, secret.Name)
+			if err != nil {
+				log.Printf("secrets reconciler: unable to rotate %s.%s: %v\n", secret.Name, namespace, err)
+				continue
+			}
+			if len(restarted) > 0 {
+				log.Printf("secrets reconciler: rotation of %s.%s restarted deployments: %v\n", secret.Name, namespace, restarted)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package secretstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// AWSSecretsManagerProvider backs OpenFaaS secrets with AWS Secrets
+// Manager, storing each secret's key/value entries as a single JSON
+// document.
+type AWSSecretsManagerProvider struct {
+	client *secretsmanager.Client
+}
+
+// NewAWSSecretsManagerProvider builds an AWSSecretsManagerProvider from
+// namespace annotation configuration. Recognised keys: "region".
+func NewAWSSecretsManagerProvider(cfg map[string]string) (*AWSSecretsManagerProvider, error) {
+	var opts []func(*config.LoadOptions) error
+	if region := cfg["region"]; region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+
+	return &AWSSecretsManagerProvider{client: secretsmanager.NewFromConfig(awsCfg)}, nil
+}
+
+func (p *AWSSecretsManagerProvider) Write(path string, data map[string][]byte) (string, error) {
+	payload, err := encode(data)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := p.client.PutSecretValue(context.Background(), &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(path),
+		SecretString: aws.String(payload),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		created, err := p.client.CreateSecret(context.Background(), &secretsmanager.CreateSecretInput{
+			Name:         aws.String(path),
+			SecretString: aws.String(payload),
+		})
+		if err != nil {
+			return "", fmt.Errorf("unable to create AWS secret %s: %w", path, err)
+		}
+		return aws.ToString(created.VersionId), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to write AWS secret %s: %w", path, err)
+	}
+
+	return aws.ToString(out.VersionId), nil
+}
+
+func (p *AWSSecretsManagerProvider) Read(path string) (map[string][]byte, string, error) {
+	out, err := p.client.GetSecretValue(context.Background(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read AWS secret %s: %w", path, err)
+	}
+
+	data, err := decode(aws.ToString(out.SecretString))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, aws.ToString(out.VersionId), nil
+}
+
+func (p *AWSSecretsManagerProvider) Delete(path string) error {
+	_, err := p.client.DeleteSecret(context.Background(), &secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete AWS secret %s: %w", path, err)
+	}
+	return nil
+}
+
+func (p *AWSSecretsManagerProvider) List(prefix string) ([]string, error) {
+	var names []string
+	var nextToken *string
+
+	for {
+		out, err := p.client.ListSecrets(context.Background(), &secretsmanager.ListSecretsInput{
+			Filters: []types.Filter{
+				{Key: types.FilterNameStringTypeName, Values: []string{prefix}},
+			},
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list AWS secrets under %s: %w", prefix, err)
+		}
+
+		for _, entry := range out.SecretList {
+			name := strings.TrimPrefix(aws.ToString(entry.Name), prefix)
+			names = append(names, name)
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+		nextToken = out.NextToken
+	}
+
+	return names, nil
+}
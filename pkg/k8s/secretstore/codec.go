@@ -0,0 +1,40 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package secretstore
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// encode flattens a secret's key/value entries into a single JSON
+// document, for stores that hold one opaque blob per path.
+func encode(data map[string][]byte) (string, error) {
+	strs := make(map[string]string, len(data))
+	for k, v := range data {
+		strs[k] = string(v)
+	}
+
+	b, err := json.Marshal(strs)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode secret data: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// decode reverses encode.
+func decode(payload string) (map[string][]byte, error) {
+	strs := map[string]string{}
+	if err := json.Unmarshal([]byte(payload), &strs); err != nil {
+		return nil, fmt.Errorf("unable to decode secret data: %w", err)
+	}
+
+	data := make(map[string][]byte, len(strs))
+	for k, v := range strs {
+		data[k] = []byte(v)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,131 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package secretstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/iterator"
+)
+
+// GCPSecretManagerProvider backs OpenFaaS secrets with GCP Secret
+// Manager, storing each secret's key/value entries as a single JSON
+// document.
+type GCPSecretManagerProvider struct {
+	client  *secretmanager.Client
+	project string
+}
+
+// NewGCPSecretManagerProvider builds a GCPSecretManagerProvider from
+// namespace annotation configuration. Requires a "project" key.
+func NewGCPSecretManagerProvider(config map[string]string) (*GCPSecretManagerProvider, error) {
+	project := config["project"]
+	if project == "" {
+		return nil, fmt.Errorf("gcp-sm provider requires a %q config value", "project")
+	}
+
+	client, err := secretmanager.NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("unable to create GCP Secret Manager client: %w", err)
+	}
+
+	return &GCPSecretManagerProvider{client: client, project: project}, nil
+}
+
+func (p *GCPSecretManagerProvider) secretName(path string) string {
+	return fmt.Sprintf("projects/%s/secrets/%s", p.project, path)
+}
+
+func (p *GCPSecretManagerProvider) Write(path string, data map[string][]byte) (string, error) {
+	payload, err := encode(data)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	name := p.secretName(path)
+
+	if _, err := p.client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: name}); err != nil {
+		_, err := p.client.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
+			Parent:   fmt.Sprintf("projects/%s", p.project),
+			SecretId: path,
+			Secret: &secretmanagerpb.Secret{
+				Replication: &secretmanagerpb.Replication{
+					Replication: &secretmanagerpb.Replication_Automatic_{
+						Automatic: &secretmanagerpb.Replication_Automatic{},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("unable to create GCP secret %s: %w", path, err)
+		}
+	}
+
+	version, err := p.client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  name,
+		Payload: &secretmanagerpb.SecretPayload{Data: []byte(payload)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to write GCP secret %s: %w", path, err)
+	}
+
+	return version.Name, nil
+}
+
+func (p *GCPSecretManagerProvider) Read(path string) (map[string][]byte, string, error) {
+	resp, err := p.client.AccessSecretVersion(context.Background(), &secretmanagerpb.AccessSecretVersionRequest{
+		Name: p.secretName(path) + "/versions/latest",
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read GCP secret %s: %w", path, err)
+	}
+
+	data, err := decode(string(resp.Payload.Data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, resp.Name, nil
+}
+
+func (p *GCPSecretManagerProvider) Delete(path string) error {
+	err := p.client.DeleteSecret(context.Background(), &secretmanagerpb.DeleteSecretRequest{
+		Name: p.secretName(path),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete GCP secret %s: %w", path, err)
+	}
+	return nil
+}
+
+func (p *GCPSecretManagerProvider) List(prefix string) ([]string, error) {
+	ctx := context.Background()
+	it := p.client.ListSecrets(ctx, &secretmanagerpb.ListSecretsRequest{
+		Parent: fmt.Sprintf("projects/%s", p.project),
+	})
+
+	var names []string
+	for {
+		secret, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to list GCP secrets under %s: %w", prefix, err)
+		}
+
+		id := secret.Name[strings.LastIndex(secret.Name, "/")+1:]
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		names = append(names, strings.TrimPrefix(id, prefix))
+	}
+
+	return names, nil
+}
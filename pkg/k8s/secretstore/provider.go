@@ -0,0 +1,55 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+// Package secretstore implements pluggable backends that can hold the
+// canonical copy of an OpenFaaS secret's data outside of the cluster,
+// while faas-netes continues to project a Kubernetes Secret into the
+// function's namespace for runtime mounting.
+package secretstore
+
+import "fmt"
+
+// Provider is implemented by an external secret store that an OpenFaaS
+// secret can be backed by.
+type Provider interface {
+	// Write stores data at path and returns an opaque revision
+	// identifier that changes whenever the stored value changes.
+	Write(path string, data map[string][]byte) (revision string, err error)
+
+	// Read fetches the data currently stored at path.
+	Read(path string) (data map[string][]byte, revision string, err error)
+
+	// Delete removes the value stored at path.
+	Delete(path string) error
+
+	// List returns the name of every secret stored under prefix, with
+	// the prefix itself stripped, so that it can be merged with locally
+	// projected Kubernetes Secrets by faas-netes.
+	List(prefix string) ([]string, error)
+}
+
+// Kind identifies a supported external secret store, as configured via a
+// namespace annotation.
+type Kind string
+
+const (
+	KindVault Kind = "vault"
+	KindAWSSM Kind = "aws-sm"
+	KindGCPSM Kind = "gcp-sm"
+)
+
+// NewProvider constructs the Provider for the given kind from its
+// configuration, typically sourced from the annotations on the
+// namespace that opted into it.
+func NewProvider(kind Kind, config map[string]string) (Provider, error) {
+	switch kind {
+	case KindVault:
+		return NewVaultProvider(config)
+	case KindAWSSM:
+		return NewAWSSecretsManagerProvider(config)
+	case KindGCPSM:
+		return NewGCPSecretManagerProvider(config)
+	default:
+		return nil, fmt.Errorf("unsupported secret store provider: %q", kind)
+	}
+}
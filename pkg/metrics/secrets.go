@@ -0,0 +1,26 @@
+// License: OpenFaaS Community Edition (CE) EULA
+// Copyright (c) 2017,2019-2024 OpenFaaS Author(s)
+
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// SecretOperationsTotal counts secret mutations handled by
+	// SecretsHandler, broken down by action, namespace and outcome.
+	SecretOperationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "openfaas_secrets_operations_total",
+		Help: "Count of secret operations performed through the OpenFaaS secrets API",
+	}, []string{"action", "namespace", "status"})
+
+	// SecretOperationDuration tracks how long secret operations take,
+	// broken down by action.
+	SecretOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "openfaas_secrets_operation_duration_seconds",
+		Help: "Time taken to complete a secret operation through the OpenFaaS secrets API",
+	}, []string{"action"})
+)
+
+func init() {
+	prometheus.MustRegister(SecretOperationsTotal, SecretOperationDuration)
+}